@@ -0,0 +1,160 @@
+package pulseaudio
+
+// #cgo pkg-config: libpulse
+// #include <pulse/pulseaudio.h>
+// #include "pulsego.h"
+import "C"
+import (
+	"fmt"
+	"unsafe"
+)
+
+// serverInfoState receives the default sink and source names reported by
+// pa_context_get_server_info.
+type serverInfoState struct {
+	mainloop          *C.pa_threaded_mainloop
+	defaultSinkName   string
+	defaultSourceName string
+}
+
+//export go_server_info_cb
+func go_server_info_cb(ctx *C.pa_context, i *C.pa_server_info, userdata unsafe.Pointer) {
+	state := (*serverInfoState)(userdata)
+	state.defaultSinkName = C.GoString(i.default_sink_name)
+	state.defaultSourceName = C.GoString(i.default_source_name)
+	C.pa_threaded_mainloop_signal(state.mainloop, 0)
+}
+
+// serverInfo returns the names of the server's current default sink and
+// source.
+func (c *Client) serverInfo() (defaultSink, defaultSource string) {
+	ret := make(chan serverInfoState, 1)
+	c.post(func() {
+		state := serverInfoState{mainloop: c.mainloop}
+		c.run(C.pa_context_get_server_info(c.context, (*[0]byte)(C.server_info_cb), unsafe.Pointer(&state)))
+		ret <- state
+	})
+	state := <-ret
+	return state.defaultSinkName, state.defaultSourceName
+}
+
+// FindSink returns the first sink for which predicate returns true.
+func (c *Client) FindSink(predicate func(*Sink) bool) (*Sink, error) {
+	for _, sink := range c.Sinks() {
+		if predicate(sink) {
+			return sink, nil
+		}
+	}
+	return nil, fmt.Errorf("PulseAudio error: no sink matched predicate")
+}
+
+// FindSource returns the first source for which predicate returns true.
+func (c *Client) FindSource(predicate func(*Source) bool) (*Source, error) {
+	for _, source := range c.Sources() {
+		if predicate(source) {
+			return source, nil
+		}
+	}
+	return nil, fmt.Errorf("PulseAudio error: no source matched predicate")
+}
+
+// FindSinkInput returns the first sink input for which predicate returns
+// true, e.g. to find the playback stream belonging to a particular
+// application so it can be moved or muted.
+func (c *Client) FindSinkInput(predicate func(*SinkInput) bool) (*SinkInput, error) {
+	for _, input := range c.SinkInputs() {
+		if predicate(input) {
+			return input, nil
+		}
+	}
+	return nil, fmt.Errorf("PulseAudio error: no sink input matched predicate")
+}
+
+// FindSourceOutput returns the first source output for which predicate
+// returns true.
+func (c *Client) FindSourceOutput(predicate func(*SourceOutput) bool) (*SourceOutput, error) {
+	for _, output := range c.SourceOutputs() {
+		if predicate(output) {
+			return output, nil
+		}
+	}
+	return nil, fmt.Errorf("PulseAudio error: no source output matched predicate")
+}
+
+// SinkByName returns the sink with the given name.
+func (c *Client) SinkByName(name string) (*Sink, error) {
+	sink, err := c.FindSink(func(s *Sink) bool { return s.Name == name })
+	if err != nil {
+		return nil, fmt.Errorf("PulseAudio error: sink %s not found", name)
+	}
+	return sink, nil
+}
+
+// SinkByProperty returns the first sink whose property list has key set to
+// value, e.g. SinkByProperty("device.description", "Headset").
+func (c *Client) SinkByProperty(key, value string) (*Sink, error) {
+	sink, err := c.FindSink(func(s *Sink) bool {
+		v, ok := s.Properties[key]
+		return ok && string(v) == value
+	})
+	if err != nil {
+		return nil, fmt.Errorf("PulseAudio error: no sink with %s=%s", key, value)
+	}
+	return sink, nil
+}
+
+// DefaultSink returns the server's current default sink.
+func (c *Client) DefaultSink() (*Sink, error) {
+	defaultSink, _ := c.serverInfo()
+	return c.SinkByName(defaultSink)
+}
+
+// DefaultSource returns the server's current default source.
+func (c *Client) DefaultSource() (*Source, error) {
+	_, defaultSource := c.serverInfo()
+	source, err := c.FindSource(func(s *Source) bool { return s.Name == defaultSource })
+	if err != nil {
+		return nil, fmt.Errorf("PulseAudio error: source %s not found", defaultSource)
+	}
+	return source, nil
+}
+
+// SetDefaultSink changes the server's default sink.
+func (c *Client) SetDefaultSink(name string) error {
+	ret := make(chan error, 1)
+	c.post(func() {
+		cname := C.CString(name)
+		defer C.free(unsafe.Pointer(cname))
+
+		state := contextSuccessState{mainloop: c.mainloop}
+		c.run(C.pa_context_set_default_sink(c.context, cname, (*[0]byte)(C.context_success_cb), unsafe.Pointer(&state)))
+
+		if state.success == 0 {
+			desc := C.GoString(C.pa_strerror(state.errno))
+			ret <- fmt.Errorf("Error when setting default sink to %s: %s", name, desc)
+			return
+		}
+		ret <- nil
+	})
+	return <-ret
+}
+
+// SetDefaultSource changes the server's default source.
+func (c *Client) SetDefaultSource(name string) error {
+	ret := make(chan error, 1)
+	c.post(func() {
+		cname := C.CString(name)
+		defer C.free(unsafe.Pointer(cname))
+
+		state := contextSuccessState{mainloop: c.mainloop}
+		c.run(C.pa_context_set_default_source(c.context, cname, (*[0]byte)(C.context_success_cb), unsafe.Pointer(&state)))
+
+		if state.success == 0 {
+			desc := C.GoString(C.pa_strerror(state.errno))
+			ret <- fmt.Errorf("Error when setting default source to %s: %s", name, desc)
+			return
+		}
+		ret <- nil
+	})
+	return <-ret
+}
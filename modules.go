@@ -0,0 +1,184 @@
+package pulseaudio
+
+// #cgo pkg-config: libpulse
+// #include <pulse/pulseaudio.h>
+// #include "pulsego.h"
+import "C"
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unsafe"
+)
+
+// Module provides information on a loaded PulseAudio module.
+type Module struct {
+	Index      uint32
+	Name       string
+	Argument   string
+	NUsed      int32
+	Properties map[string][]byte
+}
+
+func newModuleInfo(i *C.pa_module_info) *Module {
+	return &Module{
+		Index:      uint32(i.index),
+		Name:       C.GoString(i.name),
+		Argument:   C.GoString(i.argument),
+		NUsed:      int32(i.n_used),
+		Properties: convertPropertyList(i.proplist),
+	}
+}
+
+type moduleList struct {
+	client  *Client
+	modules []Module
+}
+
+// Modules queries PulseAudio for all currently loaded modules.
+func (c *Client) Modules() ([]Module, error) {
+	ret := make(chan []Module, 1)
+	c.post(func() {
+		list := moduleList{client: c}
+		op := C.pa_context_get_module_info_list(c.context, (*[0]byte)(C.module_info_cb), unsafe.Pointer(&list))
+		c.run(op)
+		ret <- list.modules
+	})
+	return <-ret, nil
+}
+
+//export go_module_info_cb
+func go_module_info_cb(i *C.pa_module_info, eol C.int, userdata unsafe.Pointer) {
+	list := (*moduleList)(userdata)
+	if eol != 0 {
+		list.client.signal()
+		return
+	}
+	list.modules = append(list.modules, *newModuleInfo(i))
+}
+
+// moduleLoadState receives the result of pa_context_load_module through its
+// index callback: the new module's index, or PA_INVALID_INDEX and an errno
+// captured while the context was still valid.
+type moduleLoadState struct {
+	mainloop *C.pa_threaded_mainloop
+	index    C.uint32_t
+	errno    C.int
+}
+
+//export go_context_index_cb
+func go_context_index_cb(ctx *C.pa_context, idx C.uint32_t, userdata unsafe.Pointer) {
+	state := (*moduleLoadState)(userdata)
+	state.index = idx
+	if idx == C.PA_INVALID_INDEX {
+		state.errno = C.pa_context_errno(ctx)
+	}
+	C.pa_threaded_mainloop_signal(state.mainloop, 0)
+}
+
+// LoadModule asks the PulseAudio server to load the module with the given
+// name, passing it args verbatim as its argument string (use
+// FormatModuleArgs to build one from key/value pairs). It returns the index
+// of the freshly loaded module.
+func (c *Client) LoadModule(name, args string) (uint32, error) {
+	type result struct {
+		index uint32
+		err   error
+	}
+	ret := make(chan result, 1)
+	c.post(func() {
+		cname := C.CString(name)
+		cargs := C.CString(args)
+		defer C.free(unsafe.Pointer(cname))
+		defer C.free(unsafe.Pointer(cargs))
+
+		state := moduleLoadState{mainloop: c.mainloop}
+		op := C.pa_context_load_module(c.context, cname, cargs, (*[0]byte)(C.context_index_cb), unsafe.Pointer(&state))
+		c.run(op)
+
+		if state.index == C.PA_INVALID_INDEX {
+			desc := C.GoString(C.pa_strerror(state.errno))
+			ret <- result{err: fmt.Errorf("Error when loading module %s: %s", name, desc)}
+			return
+		}
+		ret <- result{index: uint32(state.index)}
+	})
+	r := <-ret
+	return r.index, r.err
+}
+
+// contextSuccessState receives the result of any pa_context_*_cb_t
+// completion callback that only reports success or failure.
+type contextSuccessState struct {
+	mainloop *C.pa_threaded_mainloop
+	success  C.int
+	errno    C.int
+}
+
+//export go_context_success_cb
+func go_context_success_cb(ctx *C.pa_context, success C.int, userdata unsafe.Pointer) {
+	state := (*contextSuccessState)(userdata)
+	state.success = success
+	if success == 0 {
+		state.errno = C.pa_context_errno(ctx)
+	}
+	C.pa_threaded_mainloop_signal(state.mainloop, 0)
+}
+
+// UnloadModule asks the PulseAudio server to unload the module with the
+// given index.
+func (c *Client) UnloadModule(index uint32) error {
+	ret := make(chan error, 1)
+	c.post(func() {
+		state := contextSuccessState{mainloop: c.mainloop}
+		op := C.pa_context_unload_module(c.context, C.uint32_t(index), (*[0]byte)(C.context_success_cb), unsafe.Pointer(&state))
+		c.run(op)
+
+		if state.success == 0 {
+			desc := C.GoString(C.pa_strerror(state.errno))
+			ret <- fmt.Errorf("Error when unloading module %d: %s", index, desc)
+			return
+		}
+		ret <- nil
+	})
+	return <-ret
+}
+
+// FormatModuleArgs builds a module argument string suitable for LoadModule
+// out of key/value pairs, quoting values that contain whitespace, quotes or
+// backslashes the way PulseAudio's own argument parser expects.
+func FormatModuleArgs(args map[string]string) string {
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+quoteModuleArg(args[k]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// quoteModuleArg wraps value in double quotes and escapes embedded quotes
+// and backslashes if it contains characters that would otherwise terminate
+// it early or be taken as an escape in a module argument string. The
+// whitespace set matches what
+// PulseAudio's own argument splitter (pa_split_spaces) treats as a
+// delimiter, not just space and tab.
+func quoteModuleArg(value string) string {
+	if !strings.ContainsAny(value, " \t\n\v\f\r\"\\") {
+		return value
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range value {
+		if r == '"' || r == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
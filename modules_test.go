@@ -0,0 +1,34 @@
+package pulseaudio
+
+import "testing"
+
+func TestQuoteModuleArg(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"sink_name", "sink_name"},
+		{"has space", `"has space"`},
+		{"has\ttab", "\"has\ttab\""},
+		{"has\nnewline", "\"has\nnewline\""},
+		{`has"quote`, `"has\"quote"`},
+		{`has\backslash`, `"has\\backslash"`},
+	}
+	for _, c := range cases {
+		if got := quoteModuleArg(c.in); got != c.want {
+			t.Errorf("quoteModuleArg(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestFormatModuleArgs(t *testing.T) {
+	got := FormatModuleArgs(map[string]string{
+		"sink_name": "my sink",
+		"rate":      "44100",
+	})
+	want := `rate=44100 sink_name="my sink"`
+	if got != want {
+		t.Errorf("FormatModuleArgs(...) = %q, want %q", got, want)
+	}
+}
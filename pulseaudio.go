@@ -27,76 +27,129 @@ package pulseaudio // import "mrogalski.eu/go/pulseaudio"
 import "C"
 import (
 	"fmt"
+	"runtime/cgo"
 	"unsafe"
 )
 
-// Client maintains a connection to the PulseAudio server.
+// callbackToken lets a long-lived C callback (one that outlives the Go call
+// that installed it, like a subscribe or stream read/write callback) find
+// its way back to the Go value it belongs to, without ever handing libpulse
+// a pointer into Go-managed memory: cgo's pointer-passing rules forbid C
+// from retaining a Go pointer past the call that received it, and nothing
+// here guarantees that. The cgo.Handle is the registry; userdata is a
+// C-heap cell (outside the Go GC's purview) that simply holds the handle's
+// numeric value, which is what we actually hand to libpulse as void*.
+type callbackToken struct {
+	handle   cgo.Handle
+	userdata *C.uintptr_t
+}
+
+func newCallbackToken(v interface{}) *callbackToken {
+	userdata := (*C.uintptr_t)(C.malloc(C.size_t(unsafe.Sizeof(C.uintptr_t(0)))))
+	handle := cgo.NewHandle(v)
+	*userdata = C.uintptr_t(handle)
+	return &callbackToken{handle: handle, userdata: userdata}
+}
+
+// ptr is the void* to pass as a C callback's userdata.
+func (t *callbackToken) ptr() unsafe.Pointer {
+	return unsafe.Pointer(t.userdata)
+}
+
+// release is safe to call more than once, since Close methods built on top
+// of a callbackToken don't guard against being called twice themselves.
+func (t *callbackToken) release() {
+	if t.userdata == nil {
+		return
+	}
+	t.handle.Delete()
+	C.free(unsafe.Pointer(t.userdata))
+	t.userdata = nil
+}
+
+// callbackTokenValue recovers the Go value registered with newCallbackToken
+// from the userdata a C callback was invoked with.
+func callbackTokenValue(userdata unsafe.Pointer) interface{} {
+	return cgo.Handle(*(*C.uintptr_t)(userdata)).Value()
+}
+
+// Client maintains a connection to the PulseAudio server. The connection is
+// driven by a pa_threaded_mainloop running on its own OS thread; every call
+// into libpulse is made under the mainloop's lock, so multiple Clients can
+// be used concurrently from Go without interfering with each other.
 type Client struct {
-	mainloop    *C.struct_pa_mainloop
-	mainloopAPI *C.struct_pa_mainloop_api
-	context     *C.struct_pa_context
-	updates     chan func()
+	mainloop    *C.pa_threaded_mainloop
+	mainloopAPI *C.pa_mainloop_api
+	context     *C.pa_context
+	token       *callbackToken
+	events      chan SubscribeEvent
 }
 
 // NewClient establishes a connection to the PulseAudio server.
 func NewClient(name string) (*Client, error) {
-	mainloop := C.pa_mainloop_new()
-	mainloopAPI := C.pa_mainloop_get_api(mainloop)
+	mainloop := C.pa_threaded_mainloop_new()
+	mainloopAPI := C.pa_threaded_mainloop_get_api(mainloop)
 	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
 	context := C.pa_context_new(mainloopAPI, cname)
 
+	client := &Client{
+		mainloop:    mainloop,
+		mainloopAPI: mainloopAPI,
+		context:     context,
+	}
+	client.token = newCallbackToken(client)
+
 	if C.pa_context_connect(context, nil, C.PA_CONTEXT_NOFLAGS, nil) < 0 {
 		errno := C.pa_context_errno(context)
 		desc := C.GoString(C.pa_strerror(errno))
+		client.token.release()
+		C.pa_threaded_mainloop_free(mainloop)
 		return nil, fmt.Errorf("Connection error: %s", desc)
 	}
-loop:
+
+	if C.pa_threaded_mainloop_start(mainloop) < 0 {
+		client.token.release()
+		C.pa_threaded_mainloop_free(mainloop)
+		return nil, fmt.Errorf("Mainloop error")
+	}
+
+	client.lock()
 	for {
 		switch C.pa_context_get_state(context) {
-		case C.PA_CONTEXT_CONNECTING:
-		case C.PA_CONTEXT_AUTHORIZING:
-		case C.PA_CONTEXT_FAILED:
-			return nil, fmt.Errorf("Connection error")
 		case C.PA_CONTEXT_READY:
-			break loop
-		}
-		if C.pa_mainloop_iterate(mainloop, 1, nil) < 0 {
-			return nil, fmt.Errorf("Mainloop error")
+			client.unlock()
+			return client, nil
+		case C.PA_CONTEXT_FAILED, C.PA_CONTEXT_TERMINATED:
+			client.unlock()
+			client.Close()
+			return nil, fmt.Errorf("Connection error")
 		}
+		client.wait()
 	}
-	C.free(unsafe.Pointer(cname))
-	client := Client{
-		mainloop,
-		mainloopAPI,
-		context,
-		make(chan func(), 10),
-	}
-	go client.loop()
-	return &client, nil
 }
 
-func (c *Client) loop() {
-	for {
-		select {
-		case call := <-c.updates:
-			call()
-		default:
-			if C.pa_mainloop_iterate(c.mainloop, 1, nil) < 0 {
-				fmt.Println("Exiting PulseAudio loop")
-				return
-			}
-		}
-	}
-}
+func (c *Client) lock()   { C.pa_threaded_mainloop_lock(c.mainloop) }
+func (c *Client) unlock() { C.pa_threaded_mainloop_unlock(c.mainloop) }
+func (c *Client) wait()   { C.pa_threaded_mainloop_wait(c.mainloop) }
+func (c *Client) signal() { C.pa_threaded_mainloop_signal(c.mainloop, 0) }
 
+// post runs f with the mainloop lock held, blocking until any PulseAudio
+// operations f starts have completed. Every call into this package's C
+// bindings goes through post.
 func (c *Client) post(f func()) {
-	c.updates <- f
-	C.pa_mainloop_wakeup(c.mainloop)
+	c.lock()
+	defer c.unlock()
+	f()
 }
 
+// run blocks, releasing the mainloop lock while waiting, until op finishes.
+// Completion is signalled by whichever pa_context_*_cb_t callback was
+// passed to the call that produced op; see opSignal and the *_cb callbacks
+// in this package for how that signal reaches us.
 func (c *Client) run(op *C.pa_operation) {
 	for C.pa_operation_get_state(op) == C.PA_OPERATION_RUNNING {
-		C.pa_mainloop_iterate(c.mainloop, 1, nil)
+		c.wait()
 	}
 	if C.pa_operation_get_state(op) != C.PA_OPERATION_DONE {
 		fmt.Println("Error when running PulseAudio operation!")
@@ -104,20 +157,115 @@ func (c *Client) run(op *C.pa_operation) {
 	C.pa_operation_unref(op)
 }
 
-var callback func()
+// opSignal is passed as userdata to PulseAudio calls whose result we don't
+// care about, purely so their completion callback can wake up the run()
+// loop that's waiting on the operation.
+type opSignal struct {
+	mainloop *C.pa_threaded_mainloop
+}
+
+//export go_context_signal_cb
+func go_context_signal_cb(ctx *C.pa_context, success C.int, userdata unsafe.Pointer) {
+	sig := (*opSignal)(userdata)
+	C.pa_threaded_mainloop_signal(sig.mainloop, 0)
+}
+
+// SubscribeEventType describes what happened to the object named by a
+// SubscribeEvent.
+type SubscribeEventType int
+
+const (
+	// SubscribeEventNew indicates that an object was created.
+	SubscribeEventNew SubscribeEventType = iota
+	// SubscribeEventChange indicates that an object's state changed.
+	SubscribeEventChange
+	// SubscribeEventRemove indicates that an object was removed.
+	SubscribeEventRemove
+)
+
+// SubscribeFacility identifies the kind of object a SubscribeEvent is
+// about.
+type SubscribeFacility int
+
+const (
+	SubscribeFacilitySink SubscribeFacility = iota
+	SubscribeFacilitySource
+	SubscribeFacilitySinkInput
+	SubscribeFacilitySourceOutput
+	SubscribeFacilityModule
+	SubscribeFacilityClient
+	SubscribeFacilitySampleCache
+	SubscribeFacilityServer
+	SubscribeFacilityCard
+)
+
+// SubscribeEvent describes a single change reported by the PulseAudio
+// server to a subscribed Client.
+type SubscribeEvent struct {
+	Type     SubscribeEventType
+	Facility SubscribeFacility
+	Index    uint32
+}
+
+func convertSubscribeEvent(event C.pa_subscription_event_type_t, idx C.uint32_t) SubscribeEvent {
+	e := SubscribeEvent{Index: uint32(idx)}
+	switch event & C.PA_SUBSCRIPTION_EVENT_TYPE_MASK {
+	case C.PA_SUBSCRIPTION_EVENT_NEW:
+		e.Type = SubscribeEventNew
+	case C.PA_SUBSCRIPTION_EVENT_CHANGE:
+		e.Type = SubscribeEventChange
+	case C.PA_SUBSCRIPTION_EVENT_REMOVE:
+		e.Type = SubscribeEventRemove
+	}
+	switch event & C.PA_SUBSCRIPTION_EVENT_FACILITY_MASK {
+	case C.PA_SUBSCRIPTION_EVENT_SINK:
+		e.Facility = SubscribeFacilitySink
+	case C.PA_SUBSCRIPTION_EVENT_SOURCE:
+		e.Facility = SubscribeFacilitySource
+	case C.PA_SUBSCRIPTION_EVENT_SINK_INPUT:
+		e.Facility = SubscribeFacilitySinkInput
+	case C.PA_SUBSCRIPTION_EVENT_SOURCE_OUTPUT:
+		e.Facility = SubscribeFacilitySourceOutput
+	case C.PA_SUBSCRIPTION_EVENT_MODULE:
+		e.Facility = SubscribeFacilityModule
+	case C.PA_SUBSCRIPTION_EVENT_CLIENT:
+		e.Facility = SubscribeFacilityClient
+	case C.PA_SUBSCRIPTION_EVENT_SAMPLE_CACHE:
+		e.Facility = SubscribeFacilitySampleCache
+	case C.PA_SUBSCRIPTION_EVENT_SERVER:
+		e.Facility = SubscribeFacilityServer
+	case C.PA_SUBSCRIPTION_EVENT_CARD:
+		e.Facility = SubscribeFacilityCard
+	}
+	return e
+}
 
 //export go_context_subscribe_cb
-func go_context_subscribe_cb(event C.pa_subscription_event_type_t, idx C.uint, userdata unsafe.Pointer) {
-	callback()
+func go_context_subscribe_cb(event C.pa_subscription_event_type_t, idx C.uint32_t, userdata unsafe.Pointer) {
+	c, ok := callbackTokenValue(userdata).(*Client)
+	if !ok || c.events == nil {
+		return
+	}
+	select {
+	case c.events <- convertSubscribeEvent(event, idx):
+	default:
+		// Slow consumer: drop the event rather than block the mainloop
+		// thread, which would stall every operation of this Client.
+	}
 }
 
-// Subscribe registers specified callback to be called on every PulseAudio update.
-func (c *Client) Subscribe(cb func()) {
+// Subscribe asks the PulseAudio server to start reporting changes, and
+// returns a channel that future SubscribeEvents are delivered on. It is
+// safe to call at most once per Client.
+func (c *Client) Subscribe() chan SubscribeEvent {
+	events := make(chan SubscribeEvent, 256)
+	c.events = events
 	c.post(func() {
-		callback = cb
-		C.pa_context_set_subscribe_callback(c.context, (*[0]byte)(C.context_subscribe_cb), nil)
-		c.run(C.pa_context_subscribe(c.context, C.PA_SUBSCRIPTION_MASK_ALL, nil, nil))
+		C.pa_context_set_subscribe_callback(c.context, (*[0]byte)(C.context_subscribe_cb), c.token.ptr())
+		sig := opSignal{c.mainloop}
+		c.run(C.pa_context_subscribe(c.context, C.PA_SUBSCRIPTION_MASK_ALL, (*[0]byte)(C.context_signal_cb), unsafe.Pointer(&sig)))
 	})
+	return events
 }
 
 // ProfileInfo provides operations on PulseAudio profiles.
@@ -137,7 +285,8 @@ func (p *ProfileInfo) Activate() {
 	p.Client.post(func() {
 		cname := C.CString(p.Card.Name)
 		pname := C.CString(p.Name)
-		p.Client.run(C.pa_context_set_card_profile_by_name(p.Client.context, cname, pname, nil, nil))
+		sig := opSignal{p.Client.mainloop}
+		p.Client.run(C.pa_context_set_card_profile_by_name(p.Client.context, cname, pname, (*[0]byte)(C.context_signal_cb), unsafe.Pointer(&sig)))
 		C.free(unsafe.Pointer(pname))
 		C.free(unsafe.Pointer(cname))
 	})
@@ -308,10 +457,11 @@ func (c *Client) Cards() []*Card {
 
 //export go_card_info_cb
 func go_card_info_cb(i *C.pa_card_info, eol C.int, userdata unsafe.Pointer) {
+	list := (*cardList)(userdata)
 	if eol != 0 {
+		list.client.signal()
 		return
 	}
-	list := (*cardList)(userdata)
 	card := newCardInfo(list.client, i)
 	list.cards = append(list.cards, card)
 }
@@ -359,7 +509,8 @@ func (s *Sink) SetVolume(value float32) {
 		s.Volume[i] = uint(value * float32(C.PA_VOLUME_NORM))
 	}
 	s.Client.post(func() {
-		s.Client.run(C.pa_context_set_sink_volume_by_index(s.Client.context, C.uint(s.Index), &volume, nil, nil))
+		sig := opSignal{s.Client.mainloop}
+		s.Client.run(C.pa_context_set_sink_volume_by_index(s.Client.context, C.uint(s.Index), &volume, (*[0]byte)(C.context_signal_cb), unsafe.Pointer(&sig)))
 	})
 }
 
@@ -398,20 +549,25 @@ func (c *Client) Sinks() []*Sink {
 
 //export go_sink_info_cb
 func go_sink_info_cb(i *C.pa_sink_info, eol C.int, userdata unsafe.Pointer) {
+	list := (*sinkList)(userdata)
 	if eol != 0 {
+		list.c.signal()
 		return
 	}
-	list := (*sinkList)(userdata)
-	sink := newSinkInfo((*Client)(list.c), i)
+	sink := newSinkInfo(list.c, i)
 	list.sinks = append(list.sinks, sink)
 }
 
-// Close disconnects Client from the PulseAudio server.
+// Close disconnects Client from the PulseAudio server and stops its
+// mainloop thread.
 func (c *Client) Close() {
 	c.post(func() {
 		if C.pa_context_get_state(c.context) == C.PA_CONTEXT_READY {
 			C.pa_context_disconnect(c.context)
 		}
-		C.pa_mainloop_free(c.mainloop)
 	})
+	C.pa_threaded_mainloop_stop(c.mainloop)
+	C.pa_context_unref(c.context)
+	C.pa_threaded_mainloop_free(c.mainloop)
+	c.token.release()
 }
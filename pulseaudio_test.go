@@ -0,0 +1,31 @@
+package pulseaudio
+
+// #include <pulse/pulseaudio.h>
+import "C"
+
+import "testing"
+
+func TestConvertSubscribeEvent(t *testing.T) {
+	cases := []struct {
+		event C.pa_subscription_event_type_t
+		want  SubscribeEvent
+	}{
+		{
+			C.PA_SUBSCRIPTION_EVENT_SINK | C.PA_SUBSCRIPTION_EVENT_NEW,
+			SubscribeEvent{Type: SubscribeEventNew, Facility: SubscribeFacilitySink, Index: 3},
+		},
+		{
+			C.PA_SUBSCRIPTION_EVENT_SOURCE_OUTPUT | C.PA_SUBSCRIPTION_EVENT_CHANGE,
+			SubscribeEvent{Type: SubscribeEventChange, Facility: SubscribeFacilitySourceOutput, Index: 3},
+		},
+		{
+			C.PA_SUBSCRIPTION_EVENT_CARD | C.PA_SUBSCRIPTION_EVENT_REMOVE,
+			SubscribeEvent{Type: SubscribeEventRemove, Facility: SubscribeFacilityCard, Index: 3},
+		},
+	}
+	for _, c := range cases {
+		if got := convertSubscribeEvent(c.event, 3); got != c.want {
+			t.Errorf("convertSubscribeEvent(%d, 3) = %+v, want %+v", c.event, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package pulseaudio
+
+// #cgo pkg-config: libpulse
+// #include <pulse/pulseaudio.h>
+// #include "pulsego.h"
+import "C"
+import "unsafe"
+
+// Source provides operations on PulseAudio sources.
+type Source struct {
+	Client      *Client
+	Name        string
+	Index       uint
+	Description string
+	OwnerModule uint
+	Volume      ChannelVolume
+	Mute        bool
+	Properties  map[string][]byte
+}
+
+// GetVolume returns the volume for this audio source.
+func (s *Source) GetVolume() float32 {
+	return float32(s.Volume[0]) / float32(C.PA_VOLUME_NORM)
+}
+
+// SetVolume sets the volume for this audio source.
+func (s *Source) SetVolume(value float32) {
+	var volume C.struct_pa_cvolume
+	volume.channels = C.uchar(len(s.Volume))
+	for i := range s.Volume {
+		volume.values[i] = C.uint(value * C.PA_VOLUME_NORM)
+		s.Volume[i] = uint(value * float32(C.PA_VOLUME_NORM))
+	}
+	s.Client.post(func() {
+		sig := opSignal{s.Client.mainloop}
+		s.Client.run(C.pa_context_set_source_volume_by_index(s.Client.context, C.uint(s.Index), &volume, (*[0]byte)(C.context_signal_cb), unsafe.Pointer(&sig)))
+	})
+}
+
+func newSourceInfo(c *Client, i *C.pa_source_info) *Source {
+	source := Source{
+		Client:      c,
+		Name:        C.GoString(i.name),
+		Index:       uint(i.index),
+		Description: C.GoString(i.description),
+		OwnerModule: uint(i.owner_module),
+		Mute:        i.mute != 0,
+		Properties:  convertPropertyList(i.proplist),
+	}
+	for iter := 0; iter < int(i.volume.channels); iter++ {
+		source.Volume = append(source.Volume, uint(i.volume.values[iter]))
+	}
+	return &source
+}
+
+type sourceList struct {
+	c       *Client
+	sources []*Source
+}
+
+// Sources queries PulseAudio for all audio sources.
+func (c *Client) Sources() []*Source {
+	ret := make(chan []*Source, 1)
+	c.post(func() {
+		list := sourceList{}
+		op := C.pa_context_get_source_info_list(c.context, (*[0]byte)(C.source_info_cb), unsafe.Pointer(&list))
+		list.c = c
+		c.run(op)
+		ret <- list.sources
+	})
+	return <-ret
+}
+
+//export go_source_info_cb
+func go_source_info_cb(i *C.pa_source_info, eol C.int, userdata unsafe.Pointer) {
+	list := (*sourceList)(userdata)
+	if eol != 0 {
+		list.c.signal()
+		return
+	}
+	source := newSourceInfo(list.c, i)
+	list.sources = append(list.sources, source)
+}
+
+// SinkInput provides operations on PulseAudio playback streams connected to
+// a sink.
+type SinkInput struct {
+	Client      *Client
+	Name        string
+	Index       uint
+	Sink        uint
+	OwnerModule uint
+	Volume      ChannelVolume
+	Mute        bool
+	Properties  map[string][]byte
+}
+
+// GetVolume returns the volume for this sink input.
+func (si *SinkInput) GetVolume() float32 {
+	return float32(si.Volume[0]) / float32(C.PA_VOLUME_NORM)
+}
+
+// SetVolume sets the volume for this sink input.
+func (si *SinkInput) SetVolume(value float32) {
+	var volume C.struct_pa_cvolume
+	volume.channels = C.uchar(len(si.Volume))
+	for i := range si.Volume {
+		volume.values[i] = C.uint(value * C.PA_VOLUME_NORM)
+		si.Volume[i] = uint(value * float32(C.PA_VOLUME_NORM))
+	}
+	si.Client.post(func() {
+		sig := opSignal{si.Client.mainloop}
+		si.Client.run(C.pa_context_set_sink_input_volume(si.Client.context, C.uint(si.Index), &volume, (*[0]byte)(C.context_signal_cb), unsafe.Pointer(&sig)))
+	})
+}
+
+// SetMute mutes or unmutes this sink input.
+func (si *SinkInput) SetMute(mute bool) {
+	si.Mute = mute
+	si.Client.post(func() {
+		sig := opSignal{si.Client.mainloop}
+		si.Client.run(C.pa_context_set_sink_input_mute(si.Client.context, C.uint(si.Index), boolToInt(mute), (*[0]byte)(C.context_signal_cb), unsafe.Pointer(&sig)))
+	})
+}
+
+// MoveTo reconnects this sink input to sink, e.g. to route a single
+// application's playback onto a different (possibly virtual) sink.
+func (si *SinkInput) MoveTo(sink *Sink) {
+	si.Client.post(func() {
+		sig := opSignal{si.Client.mainloop}
+		si.Client.run(C.pa_context_move_sink_input_by_index(si.Client.context, C.uint(si.Index), C.uint(sink.Index), (*[0]byte)(C.context_signal_cb), unsafe.Pointer(&sig)))
+	})
+	si.Sink = sink.Index
+}
+
+func newSinkInputInfo(c *Client, i *C.pa_sink_input_info) *SinkInput {
+	input := SinkInput{
+		Client:      c,
+		Name:        C.GoString(i.name),
+		Index:       uint(i.index),
+		Sink:        uint(i.sink),
+		OwnerModule: uint(i.owner_module),
+		Mute:        i.mute != 0,
+		Properties:  convertPropertyList(i.proplist),
+	}
+	for iter := 0; iter < int(i.volume.channels); iter++ {
+		input.Volume = append(input.Volume, uint(i.volume.values[iter]))
+	}
+	return &input
+}
+
+type sinkInputList struct {
+	c      *Client
+	inputs []*SinkInput
+}
+
+// SinkInputs queries PulseAudio for all playback streams currently
+// connected to a sink.
+func (c *Client) SinkInputs() []*SinkInput {
+	ret := make(chan []*SinkInput, 1)
+	c.post(func() {
+		list := sinkInputList{}
+		op := C.pa_context_get_sink_input_info_list(c.context, (*[0]byte)(C.sink_input_info_cb), unsafe.Pointer(&list))
+		list.c = c
+		c.run(op)
+		ret <- list.inputs
+	})
+	return <-ret
+}
+
+//export go_sink_input_info_cb
+func go_sink_input_info_cb(i *C.pa_sink_input_info, eol C.int, userdata unsafe.Pointer) {
+	list := (*sinkInputList)(userdata)
+	if eol != 0 {
+		list.c.signal()
+		return
+	}
+	input := newSinkInputInfo(list.c, i)
+	list.inputs = append(list.inputs, input)
+}
+
+// SourceOutput provides operations on PulseAudio record streams connected
+// to a source.
+type SourceOutput struct {
+	Client      *Client
+	Name        string
+	Index       uint
+	Source      uint
+	OwnerModule uint
+	Volume      ChannelVolume
+	Mute        bool
+	Properties  map[string][]byte
+}
+
+// GetVolume returns the volume for this source output.
+func (so *SourceOutput) GetVolume() float32 {
+	return float32(so.Volume[0]) / float32(C.PA_VOLUME_NORM)
+}
+
+// SetVolume sets the volume for this source output.
+func (so *SourceOutput) SetVolume(value float32) {
+	var volume C.struct_pa_cvolume
+	volume.channels = C.uchar(len(so.Volume))
+	for i := range so.Volume {
+		volume.values[i] = C.uint(value * C.PA_VOLUME_NORM)
+		so.Volume[i] = uint(value * float32(C.PA_VOLUME_NORM))
+	}
+	so.Client.post(func() {
+		sig := opSignal{so.Client.mainloop}
+		so.Client.run(C.pa_context_set_source_output_volume(so.Client.context, C.uint(so.Index), &volume, (*[0]byte)(C.context_signal_cb), unsafe.Pointer(&sig)))
+	})
+}
+
+// SetMute mutes or unmutes this source output.
+func (so *SourceOutput) SetMute(mute bool) {
+	so.Mute = mute
+	so.Client.post(func() {
+		sig := opSignal{so.Client.mainloop}
+		so.Client.run(C.pa_context_set_source_output_mute(so.Client.context, C.uint(so.Index), boolToInt(mute), (*[0]byte)(C.context_signal_cb), unsafe.Pointer(&sig)))
+	})
+}
+
+// MoveTo reconnects this source output to source, e.g. to route a single
+// application's recording from a different (possibly virtual) source.
+func (so *SourceOutput) MoveTo(source *Source) {
+	so.Client.post(func() {
+		sig := opSignal{so.Client.mainloop}
+		so.Client.run(C.pa_context_move_source_output_by_index(so.Client.context, C.uint(so.Index), C.uint(source.Index), (*[0]byte)(C.context_signal_cb), unsafe.Pointer(&sig)))
+	})
+	so.Source = source.Index
+}
+
+func newSourceOutputInfo(c *Client, i *C.pa_source_output_info) *SourceOutput {
+	output := SourceOutput{
+		Client:      c,
+		Name:        C.GoString(i.name),
+		Index:       uint(i.index),
+		Source:      uint(i.source),
+		OwnerModule: uint(i.owner_module),
+		Mute:        i.mute != 0,
+		Properties:  convertPropertyList(i.proplist),
+	}
+	for iter := 0; iter < int(i.volume.channels); iter++ {
+		output.Volume = append(output.Volume, uint(i.volume.values[iter]))
+	}
+	return &output
+}
+
+type sourceOutputList struct {
+	c       *Client
+	outputs []*SourceOutput
+}
+
+// SourceOutputs queries PulseAudio for all record streams currently
+// connected to a source.
+func (c *Client) SourceOutputs() []*SourceOutput {
+	ret := make(chan []*SourceOutput, 1)
+	c.post(func() {
+		list := sourceOutputList{}
+		op := C.pa_context_get_source_output_info_list(c.context, (*[0]byte)(C.source_output_info_cb), unsafe.Pointer(&list))
+		list.c = c
+		c.run(op)
+		ret <- list.outputs
+	})
+	return <-ret
+}
+
+//export go_source_output_info_cb
+func go_source_output_info_cb(i *C.pa_source_output_info, eol C.int, userdata unsafe.Pointer) {
+	list := (*sourceOutputList)(userdata)
+	if eol != 0 {
+		list.c.signal()
+		return
+	}
+	output := newSourceOutputInfo(list.c, i)
+	list.outputs = append(list.outputs, output)
+}
+
+func boolToInt(b bool) C.int {
+	if b {
+		return 1
+	}
+	return 0
+}
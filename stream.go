@@ -0,0 +1,474 @@
+package pulseaudio
+
+// #cgo pkg-config: libpulse
+// #include <pulse/pulseaudio.h>
+// #include "pulsego.h"
+import "C"
+import (
+	"fmt"
+	"unsafe"
+)
+
+//export go_stream_notify_cb
+func go_stream_notify_cb(stream *C.pa_stream, userdata unsafe.Pointer) {
+	sig := (*opSignal)(userdata)
+	C.pa_threaded_mainloop_signal(sig.mainloop, 0)
+}
+
+// SampleFormat identifies the on-the-wire encoding of samples in a
+// SampleSpec.
+type SampleFormat int
+
+const (
+	SampleFormatU8 SampleFormat = iota
+	SampleFormatS16LE
+	SampleFormatS32LE
+	SampleFormatFloat32LE
+)
+
+func (f SampleFormat) paFormat() (C.pa_sample_format_t, error) {
+	switch f {
+	case SampleFormatU8:
+		return C.PA_SAMPLE_U8, nil
+	case SampleFormatS16LE:
+		return C.PA_SAMPLE_S16LE, nil
+	case SampleFormatS32LE:
+		return C.PA_SAMPLE_S32LE, nil
+	case SampleFormatFloat32LE:
+		return C.PA_SAMPLE_FLOAT32LE, nil
+	default:
+		return C.PA_SAMPLE_INVALID, fmt.Errorf("pulseaudio: unknown sample format %d", f)
+	}
+}
+
+// SampleSpec describes the format of the audio carried by a stream.
+type SampleSpec struct {
+	Format   SampleFormat
+	Rate     uint32
+	Channels uint8
+}
+
+func (s SampleSpec) paSampleSpec() (C.pa_sample_spec, error) {
+	format, err := s.Format.paFormat()
+	if err != nil {
+		return C.pa_sample_spec{}, err
+	}
+	return C.pa_sample_spec{
+		format:   format,
+		rate:     C.uint32_t(s.Rate),
+		channels: C.uint8_t(s.Channels),
+	}, nil
+}
+
+// ChannelPosition identifies a speaker position within a ChannelMap.
+type ChannelPosition int
+
+const (
+	ChannelPositionMono ChannelPosition = iota
+	ChannelPositionFrontLeft
+	ChannelPositionFrontRight
+	ChannelPositionFrontCenter
+	ChannelPositionRearLeft
+	ChannelPositionRearRight
+	ChannelPositionLFE
+)
+
+func (p ChannelPosition) paPosition() (C.pa_channel_position_t, error) {
+	switch p {
+	case ChannelPositionMono:
+		return C.PA_CHANNEL_POSITION_MONO, nil
+	case ChannelPositionFrontLeft:
+		return C.PA_CHANNEL_POSITION_FRONT_LEFT, nil
+	case ChannelPositionFrontRight:
+		return C.PA_CHANNEL_POSITION_FRONT_RIGHT, nil
+	case ChannelPositionFrontCenter:
+		return C.PA_CHANNEL_POSITION_FRONT_CENTER, nil
+	case ChannelPositionRearLeft:
+		return C.PA_CHANNEL_POSITION_REAR_LEFT, nil
+	case ChannelPositionRearRight:
+		return C.PA_CHANNEL_POSITION_REAR_RIGHT, nil
+	case ChannelPositionLFE:
+		return C.PA_CHANNEL_POSITION_LFE, nil
+	default:
+		return C.PA_CHANNEL_POSITION_INVALID, fmt.Errorf("pulseaudio: unknown channel position %d", p)
+	}
+}
+
+// ChannelMap describes how the channels in a SampleSpec map onto speaker
+// positions. A nil *ChannelMap asks PulseAudio to pick its default mapping
+// for the stream's channel count.
+type ChannelMap struct {
+	Positions []ChannelPosition
+}
+
+func (m *ChannelMap) paChannelMap() (*C.pa_channel_map, error) {
+	if m == nil || len(m.Positions) == 0 {
+		return nil, nil
+	}
+	if len(m.Positions) > C.PA_CHANNELS_MAX {
+		return nil, fmt.Errorf("pulseaudio: channel map has %d channels, more than PA_CHANNELS_MAX (%d)", len(m.Positions), C.PA_CHANNELS_MAX)
+	}
+	var cm C.pa_channel_map
+	cm.channels = C.uint8_t(len(m.Positions))
+	for i, p := range m.Positions {
+		pos, err := p.paPosition()
+		if err != nil {
+			return nil, err
+		}
+		cm._map[i] = pos
+	}
+	return &cm, nil
+}
+
+// BufferAttr tunes the internal buffering of a stream. A nil *BufferAttr
+// lets the server pick its own defaults for every field. Within a non-nil
+// BufferAttr, a zero field likewise asks the server to pick that field's
+// own default rather than requesting a zero-length buffer.
+type BufferAttr struct {
+	MaxLength    uint32
+	TargetLength uint32 // tlength; playback streams only.
+	PreBuf       uint32 // playback streams only.
+	MinReq       uint32 // playback streams only.
+	FragSize     uint32 // record streams only.
+}
+
+// bufferAttrOrDefault maps a zero BufferAttr field onto PulseAudio's
+// (uint32_t)-1 "let the server decide" sentinel, so a caller that only
+// sets some fields of a BufferAttr doesn't inadvertently ask for
+// zero-length buffers on the rest.
+func bufferAttrOrDefault(v uint32) C.uint32_t {
+	if v == 0 {
+		return 0xFFFFFFFF
+	}
+	return C.uint32_t(v)
+}
+
+func (a *BufferAttr) paBufferAttr() *C.pa_buffer_attr {
+	if a == nil {
+		return nil
+	}
+	return &C.pa_buffer_attr{
+		maxlength: bufferAttrOrDefault(a.MaxLength),
+		tlength:   bufferAttrOrDefault(a.TargetLength),
+		prebuf:    bufferAttrOrDefault(a.PreBuf),
+		minreq:    bufferAttrOrDefault(a.MinReq),
+		fragsize:  bufferAttrOrDefault(a.FragSize),
+	}
+}
+
+func waitForStreamReady(c *Client, stream *C.pa_stream) error {
+	for {
+		switch C.pa_stream_get_state(stream) {
+		case C.PA_STREAM_READY:
+			return nil
+		case C.PA_STREAM_FAILED, C.PA_STREAM_TERMINATED:
+			errno := C.pa_context_errno(c.context)
+			return fmt.Errorf("Error when connecting PulseAudio stream: %s", C.GoString(C.pa_strerror(errno)))
+		}
+		c.wait()
+	}
+}
+
+// PlaybackStream is a connected playback stream opened with
+// Client.NewPlaybackStream. It implements io.Writer; Write blocks until
+// PulseAudio has room to accept the samples.
+type PlaybackStream struct {
+	client   *Client
+	stream   *C.pa_stream
+	token    *callbackToken
+	writable chan struct{}
+}
+
+//export go_stream_write_cb
+func go_stream_write_cb(stream *C.pa_stream, nbytes C.size_t, userdata unsafe.Pointer) {
+	ps, ok := callbackTokenValue(userdata).(*PlaybackStream)
+	if !ok {
+		return
+	}
+	select {
+	case ps.writable <- struct{}{}:
+	default:
+	}
+}
+
+// NewPlaybackStream opens a playback stream named name, connected to sink
+// (the empty string for the default sink). channelMap may be nil to accept
+// PulseAudio's default mapping for the sample spec's channel count. attr
+// may be nil to let the server pick its own buffering.
+func (c *Client) NewPlaybackStream(name string, spec SampleSpec, channelMap *ChannelMap, sink string, attr *BufferAttr) (*PlaybackStream, error) {
+	ss, err := spec.paSampleSpec()
+	if err != nil {
+		return nil, err
+	}
+	cm, err := channelMap.paChannelMap()
+	if err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		stream *PlaybackStream
+		err    error
+	}
+	ret := make(chan result, 1)
+	c.post(func() {
+		cname := C.CString(name)
+		defer C.free(unsafe.Pointer(cname))
+
+		stream := C.pa_stream_new(c.context, cname, &ss, cm)
+		if stream == nil {
+			errno := C.pa_context_errno(c.context)
+			ret <- result{err: fmt.Errorf("Error when creating PulseAudio stream: %s", C.GoString(C.pa_strerror(errno)))}
+			return
+		}
+
+		ps := &PlaybackStream{client: c, stream: stream, writable: make(chan struct{}, 1)}
+		ps.token = newCallbackToken(ps)
+		C.pa_stream_set_write_callback(stream, (*[0]byte)(C.stream_write_cb), ps.token.ptr())
+		sig := opSignal{c.mainloop}
+		C.pa_stream_set_state_callback(stream, (*[0]byte)(C.stream_notify_cb), unsafe.Pointer(&sig))
+
+		var csink *C.char
+		if sink != "" {
+			csink = C.CString(sink)
+			defer C.free(unsafe.Pointer(csink))
+		}
+
+		if C.pa_stream_connect_playback(stream, csink, attr.paBufferAttr(), C.PA_STREAM_NOFLAGS, nil, nil) < 0 {
+			errno := C.pa_context_errno(c.context)
+			ps.token.release()
+			C.pa_stream_unref(stream)
+			ret <- result{err: fmt.Errorf("Error when connecting PulseAudio stream: %s", C.GoString(C.pa_strerror(errno)))}
+			return
+		}
+
+		if err := waitForStreamReady(c, stream); err != nil {
+			ps.token.release()
+			C.pa_stream_unref(stream)
+			ret <- result{err: err}
+			return
+		}
+		ret <- result{stream: ps}
+	})
+	r := <-ret
+	return r.stream, r.err
+}
+
+// Write implements io.Writer, blocking until PulseAudio reports enough room
+// in the stream's playback buffer to accept p.
+func (ps *PlaybackStream) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		var avail C.size_t
+		ps.client.post(func() {
+			avail = C.pa_stream_writable_size(ps.stream)
+		})
+		if avail == 0 {
+			<-ps.writable
+			continue
+		}
+
+		chunk := p
+		if C.size_t(len(chunk)) > avail {
+			chunk = chunk[:avail]
+		}
+
+		var writeErr error
+		ps.client.post(func() {
+			if C.pa_stream_write(ps.stream, unsafe.Pointer(&chunk[0]), C.size_t(len(chunk)), nil, 0, C.PA_SEEK_RELATIVE) < 0 {
+				errno := C.pa_context_errno(ps.client.context)
+				writeErr = fmt.Errorf("Error when writing PulseAudio stream: %s", C.GoString(C.pa_strerror(errno)))
+			}
+		})
+		if writeErr != nil {
+			return n, writeErr
+		}
+		n += len(chunk)
+		p = p[len(chunk):]
+	}
+	return n, nil
+}
+
+// Cork pauses or resumes playback.
+func (ps *PlaybackStream) Cork(corked bool) {
+	ps.client.post(func() {
+		sig := opSignal{ps.client.mainloop}
+		ps.client.run(C.pa_stream_cork(ps.stream, boolToInt(corked), (*[0]byte)(C.context_signal_cb), unsafe.Pointer(&sig)))
+	})
+}
+
+// Flush discards any data already buffered by the server but not yet
+// played.
+func (ps *PlaybackStream) Flush() {
+	ps.client.post(func() {
+		sig := opSignal{ps.client.mainloop}
+		ps.client.run(C.pa_stream_flush(ps.stream, (*[0]byte)(C.context_signal_cb), unsafe.Pointer(&sig)))
+	})
+}
+
+// Drain waits until all buffered data has actually been played.
+func (ps *PlaybackStream) Drain() {
+	ps.client.post(func() {
+		sig := opSignal{ps.client.mainloop}
+		ps.client.run(C.pa_stream_drain(ps.stream, (*[0]byte)(C.context_signal_cb), unsafe.Pointer(&sig)))
+	})
+}
+
+// Close disconnects the stream and releases it.
+func (ps *PlaybackStream) Close() {
+	ps.client.post(func() {
+		C.pa_stream_disconnect(ps.stream)
+		C.pa_stream_unref(ps.stream)
+	})
+	ps.token.release()
+}
+
+// RecordStream is a connected record stream opened with
+// Client.NewRecordStream. It implements io.Reader; Read blocks until
+// PulseAudio has delivered some recorded audio, and may return fewer bytes
+// than len(p).
+type RecordStream struct {
+	client   *Client
+	stream   *C.pa_stream
+	token    *callbackToken
+	readable chan struct{}
+	pending  []byte
+}
+
+//export go_stream_read_cb
+func go_stream_read_cb(stream *C.pa_stream, nbytes C.size_t, userdata unsafe.Pointer) {
+	rs, ok := callbackTokenValue(userdata).(*RecordStream)
+	if !ok {
+		return
+	}
+	select {
+	case rs.readable <- struct{}{}:
+	default:
+	}
+}
+
+// NewRecordStream opens a record stream named name, connected to source
+// (the empty string for the default source). channelMap may be nil to
+// accept PulseAudio's default mapping for the sample spec's channel count.
+// attr may be nil to let the server pick its own buffering.
+func (c *Client) NewRecordStream(name string, spec SampleSpec, channelMap *ChannelMap, source string, attr *BufferAttr) (*RecordStream, error) {
+	ss, err := spec.paSampleSpec()
+	if err != nil {
+		return nil, err
+	}
+	cm, err := channelMap.paChannelMap()
+	if err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		stream *RecordStream
+		err    error
+	}
+	ret := make(chan result, 1)
+	c.post(func() {
+		cname := C.CString(name)
+		defer C.free(unsafe.Pointer(cname))
+
+		stream := C.pa_stream_new(c.context, cname, &ss, cm)
+		if stream == nil {
+			errno := C.pa_context_errno(c.context)
+			ret <- result{err: fmt.Errorf("Error when creating PulseAudio stream: %s", C.GoString(C.pa_strerror(errno)))}
+			return
+		}
+
+		rs := &RecordStream{client: c, stream: stream, readable: make(chan struct{}, 1)}
+		rs.token = newCallbackToken(rs)
+		C.pa_stream_set_read_callback(stream, (*[0]byte)(C.stream_read_cb), rs.token.ptr())
+		sig := opSignal{c.mainloop}
+		C.pa_stream_set_state_callback(stream, (*[0]byte)(C.stream_notify_cb), unsafe.Pointer(&sig))
+
+		var csource *C.char
+		if source != "" {
+			csource = C.CString(source)
+			defer C.free(unsafe.Pointer(csource))
+		}
+
+		if C.pa_stream_connect_record(stream, csource, attr.paBufferAttr(), C.PA_STREAM_NOFLAGS) < 0 {
+			errno := C.pa_context_errno(c.context)
+			rs.token.release()
+			C.pa_stream_unref(stream)
+			ret <- result{err: fmt.Errorf("Error when connecting PulseAudio stream: %s", C.GoString(C.pa_strerror(errno)))}
+			return
+		}
+
+		if err := waitForStreamReady(c, stream); err != nil {
+			rs.token.release()
+			C.pa_stream_unref(stream)
+			ret <- result{err: err}
+			return
+		}
+		ret <- result{stream: rs}
+	})
+	r := <-ret
+	return r.stream, r.err
+}
+
+// Read implements io.Reader, blocking until PulseAudio has delivered at
+// least one sample of recorded audio.
+func (rs *RecordStream) Read(p []byte) (int, error) {
+	for len(rs.pending) == 0 {
+		var chunk []byte
+		var drained bool
+		var readErr error
+		rs.client.post(func() {
+			var ptr unsafe.Pointer
+			var size C.size_t
+			if C.pa_stream_peek(rs.stream, &ptr, &size) < 0 {
+				errno := C.pa_context_errno(rs.client.context)
+				readErr = fmt.Errorf("Error when reading PulseAudio stream: %s", C.GoString(C.pa_strerror(errno)))
+				return
+			}
+			if size == 0 {
+				drained = true
+				return
+			}
+			if ptr != nil {
+				chunk = C.GoBytes(ptr, C.int(size))
+			}
+			C.pa_stream_drop(rs.stream)
+		})
+		if readErr != nil {
+			return 0, readErr
+		}
+		if len(chunk) > 0 {
+			rs.pending = chunk
+			break
+		}
+		if drained {
+			<-rs.readable
+		}
+	}
+	n := copy(p, rs.pending)
+	rs.pending = rs.pending[n:]
+	return n, nil
+}
+
+// Cork pauses or resumes recording.
+func (rs *RecordStream) Cork(corked bool) {
+	rs.client.post(func() {
+		sig := opSignal{rs.client.mainloop}
+		rs.client.run(C.pa_stream_cork(rs.stream, boolToInt(corked), (*[0]byte)(C.context_signal_cb), unsafe.Pointer(&sig)))
+	})
+}
+
+// Flush discards any data already buffered by the server that hasn't been
+// delivered to Read yet.
+func (rs *RecordStream) Flush() {
+	rs.client.post(func() {
+		sig := opSignal{rs.client.mainloop}
+		rs.client.run(C.pa_stream_flush(rs.stream, (*[0]byte)(C.context_signal_cb), unsafe.Pointer(&sig)))
+	})
+}
+
+// Close disconnects the stream and releases it.
+func (rs *RecordStream) Close() {
+	rs.client.post(func() {
+		C.pa_stream_disconnect(rs.stream)
+		C.pa_stream_unref(rs.stream)
+	})
+	rs.token.release()
+}
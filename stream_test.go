@@ -0,0 +1,53 @@
+package pulseaudio
+
+// #include <pulse/pulseaudio.h>
+import "C"
+
+import "testing"
+
+func TestBufferAttrOrDefault(t *testing.T) {
+	if got := bufferAttrOrDefault(0); got != 0xFFFFFFFF {
+		t.Errorf("bufferAttrOrDefault(0) = %#x, want 0xFFFFFFFF", got)
+	}
+	if got := bufferAttrOrDefault(1024); got != 1024 {
+		t.Errorf("bufferAttrOrDefault(1024) = %d, want 1024", got)
+	}
+}
+
+func TestChannelMapPaChannelMap(t *testing.T) {
+	if cm, err := (*ChannelMap)(nil).paChannelMap(); cm != nil || err != nil {
+		t.Errorf("nil ChannelMap.paChannelMap() = (%v, %v), want (nil, nil)", cm, err)
+	}
+
+	if cm, err := (&ChannelMap{}).paChannelMap(); cm != nil || err != nil {
+		t.Errorf("empty ChannelMap.paChannelMap() = (%v, %v), want (nil, nil)", cm, err)
+	}
+
+	m := &ChannelMap{Positions: []ChannelPosition{ChannelPositionFrontLeft, ChannelPositionFrontRight}}
+	cm, err := m.paChannelMap()
+	if err != nil {
+		t.Fatalf("paChannelMap() error = %v", err)
+	}
+	if int(cm.channels) != len(m.Positions) {
+		t.Errorf("paChannelMap().channels = %d, want %d", cm.channels, len(m.Positions))
+	}
+
+	tooMany := make([]ChannelPosition, C.PA_CHANNELS_MAX+1)
+	if _, err := (&ChannelMap{Positions: tooMany}).paChannelMap(); err == nil {
+		t.Errorf("paChannelMap() with %d channels: got nil error, want one", len(tooMany))
+	}
+
+	invalid := &ChannelMap{Positions: []ChannelPosition{ChannelPosition(999)}}
+	if _, err := invalid.paChannelMap(); err == nil {
+		t.Error("paChannelMap() with an unknown position: got nil error, want one")
+	}
+}
+
+func TestSampleFormatPaFormat(t *testing.T) {
+	if _, err := SampleFormatS16LE.paFormat(); err != nil {
+		t.Errorf("paFormat() for SampleFormatS16LE: %v", err)
+	}
+	if _, err := SampleFormat(999).paFormat(); err == nil {
+		t.Error("paFormat() for an unknown SampleFormat: got nil error, want one")
+	}
+}